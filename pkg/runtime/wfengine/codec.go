@@ -0,0 +1,262 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package wfengine
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// payloadCodecMetadataKey is the StartRequest.Options / StateResponse.Metadata key used to
+// record which Codec encoded a workflow's input/output, so Get and Start can agree on how to
+// round-trip the payload.
+const payloadCodecMetadataKey = "dapr.workflow.payload_codec"
+
+// Codec encodes and decodes workflow input/output payloads before they're handed to (or read
+// back from) the durabletask backend. Name identifies the codec in the
+// "dapr.workflow.payload_codec" metadata key so Get can select the matching Decode on read-back.
+type Codec interface {
+	Name() string
+	Encode(data []byte) ([]byte, error)
+	Decode(data []byte) ([]byte, error)
+
+	// TextSafe reports whether Decode always produces valid UTF-8 text, safe to store directly in
+	// a map[string]string metadata field. Codecs that can decode to arbitrary binary (e.g. the
+	// protobuf codec) must report false so decodePayloadField base64-encodes the result instead of
+	// handing it to a later JSON-marshal, which would otherwise silently corrupt it.
+	TextSafe() bool
+}
+
+// jsonCodec is the default, backwards-compatible codec: it passes bytes through unchanged, since
+// durabletask-go already treats Input/Output as opaque JSON-serializable payloads.
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string                       { return "json" }
+func (jsonCodec) Encode(data []byte) ([]byte, error) { return data, nil }
+func (jsonCodec) Decode(data []byte) ([]byte, error) { return data, nil }
+func (jsonCodec) TextSafe() bool                     { return true }
+
+// protobufAnyCodec wraps/unwraps payloads as a serialized google.protobuf.Any, letting callers
+// pass already-marshaled protobuf messages through the workflow engine without a JSON hop.
+type protobufAnyCodec struct{}
+
+func (protobufAnyCodec) Name() string { return "protobuf" }
+
+// TextSafe is false: the unwrapped Any.Value is arbitrary protobuf-marshaled bytes, not UTF-8 text.
+func (protobufAnyCodec) TextSafe() bool { return false }
+
+func (protobufAnyCodec) Encode(data []byte) ([]byte, error) {
+	any := &anypb.Any{Value: data}
+	out, err := proto.Marshal(any)
+	if err != nil {
+		return nil, fmt.Errorf("protobuf codec: failed to encode payload: %w", err)
+	}
+	return out, nil
+}
+
+func (protobufAnyCodec) Decode(data []byte) ([]byte, error) {
+	var any anypb.Any
+	if err := proto.Unmarshal(data, &any); err != nil {
+		return nil, fmt.Errorf("protobuf codec: failed to decode payload: %w", err)
+	}
+	return any.Value, nil
+}
+
+// messagePackCodec re-encodes a JSON payload as MessagePack, and back again on read-back. Encode
+// parses the JSON into a generic value first rather than msgpack-wrapping the raw JSON bytes as an
+// opaque blob, so the stored payload is genuinely MessagePack-native and readable by any
+// msgpack-capable consumer, not just one that knows to unwrap a "bin" field and re-parse it as
+// JSON.
+type messagePackCodec struct{}
+
+func (messagePackCodec) Name() string { return "msgpack" }
+
+// TextSafe is true: Decode always re-encodes as JSON before returning.
+func (messagePackCodec) TextSafe() bool { return true }
+
+func (messagePackCodec) Encode(data []byte) ([]byte, error) {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("msgpack codec: failed to parse payload as JSON: %w", err)
+	}
+
+	out, err := msgpack.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("msgpack codec: failed to encode payload: %w", err)
+	}
+	return out, nil
+}
+
+func (messagePackCodec) Decode(data []byte) ([]byte, error) {
+	var value interface{}
+	if err := msgpack.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("msgpack codec: failed to decode payload: %w", err)
+	}
+
+	out, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("msgpack codec: failed to re-encode payload as JSON: %w", err)
+	}
+	return out, nil
+}
+
+// envelopeSeparator delimits the codec name from the payload in the bytes durabletask actually
+// stores, so Get can tell which Codec to use for Decode without a side channel.
+const envelopeSeparator = '\x00'
+
+// encodePayloadEnvelope prefixes an encoded payload with its codec name so it can be decoded
+// without out-of-band knowledge of which codec produced it.
+func encodePayloadEnvelope(codecName string, encoded []byte) []byte {
+	envelope := make([]byte, 0, len(codecName)+1+len(encoded))
+	envelope = append(envelope, []byte(codecName)...)
+	envelope = append(envelope, envelopeSeparator)
+	return append(envelope, encoded...)
+}
+
+// decodePayloadEnvelope splits a stored payload back into the codec name that produced it and
+// the codec-specific encoded bytes.
+func decodePayloadEnvelope(envelope []byte) (codecName string, encoded []byte, err error) {
+	idx := -1
+	for i, b := range envelope {
+		if b == envelopeSeparator {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return "", nil, fmt.Errorf("payload envelope is missing its codec-name prefix")
+	}
+	return string(envelope[:idx]), envelope[idx+1:], nil
+}
+
+// codecByName returns the built-in Codec registered under name, or an error if name is unknown.
+// The AES-GCM envelope codec isn't included here since it requires a per-component key lookup and
+// a reference to which secret store resolves it; see resolveEncryptedCodec.
+func codecByName(name string) (Codec, error) {
+	switch name {
+	case "", jsonCodec{}.Name():
+		return jsonCodec{}, nil
+	case protobufAnyCodec{}.Name():
+		return protobufAnyCodec{}, nil
+	case messagePackCodec{}.Name():
+		return messagePackCodec{}, nil
+	default:
+		return nil, fmt.Errorf("unknown payload codec '%s'", name)
+	}
+}
+
+// resolveCodec picks the Codec a Start/Get call should use: the codec named in reqOptions, if
+// any, otherwise the component's configured default. keyRef, if present, is a
+// "<secret-store-name>/<secret-name>" reference used to resolve the encrypted codec's key.
+func (c *workflowEngineComponent) resolveCodec(ctx context.Context, reqOptions map[string]string) (Codec, error) {
+	name := ""
+	if reqOptions != nil {
+		name = reqOptions[payloadCodecMetadataKey]
+	}
+	if name == "" {
+		if c.defaultCodec != nil {
+			return c.defaultCodec, nil
+		}
+		return jsonCodec{}, nil
+	}
+
+	if name != encryptedCodecName {
+		return codecByName(name)
+	}
+
+	keyRef := ""
+	if reqOptions != nil {
+		keyRef = reqOptions["dapr.workflow.payload_codec_key"]
+	}
+	return c.resolveEncryptedCodec(ctx, keyRef)
+}
+
+// resolveEncryptedCodec resolves the encrypted codec's AES-GCM key given a
+// "<secret-store-name>/<secret-name>" reference, calling GetSecret against whichever secret store
+// component is registered under that name.
+func (c *workflowEngineComponent) resolveEncryptedCodec(ctx context.Context, keyRef string) (Codec, error) {
+	storeName, secretName, ok := strings.Cut(keyRef, "/")
+	if !ok {
+		return nil, fmt.Errorf("dapr.workflow.payload_codec_key must be in '<secret-store-name>/<secret-name>' form")
+	}
+	client, ok := c.secretStores(storeName)
+	if !ok {
+		return nil, fmt.Errorf("encrypted codec requested but secret store '%s' is not configured", storeName)
+	}
+	return newEncryptedCodec(ctx, client, storeName, secretName)
+}
+
+// decodePayloadField decodes a durabletask-go SerializedInput/SerializedOutput string back into
+// its original payload. durabletask JSON-serializes whatever bytes Start handed it, so this
+// first undoes that JSON encoding, then unwraps the codec envelope and runs the matching Codec's
+// Decode. Any failure along the way falls back to returning the raw serialized string unchanged,
+// so older instances started before this feature existed still round-trip.
+//
+// The result is stored straight into a map[string]string metadata field that's later JSON-
+// marshaled for the response, which would silently corrupt non-UTF-8 bytes (e.g. a protobuf
+// payload); codecs that aren't TextSafe get their decoded output base64-encoded here instead.
+func (c *workflowEngineComponent) decodePayloadField(ctx context.Context, instanceID, serialized string) string {
+	if serialized == "" {
+		return serialized
+	}
+
+	var raw []byte
+	if err := json.Unmarshal([]byte(serialized), &raw); err != nil {
+		return serialized
+	}
+
+	codecName, payload, err := decodePayloadEnvelope(raw)
+	if err != nil {
+		return serialized
+	}
+
+	codec, err := c.codecForDecode(ctx, instanceID, codecName)
+	if err != nil {
+		c.logger.Warnf("failed to resolve codec '%s' for workflow '%s': %v", codecName, instanceID, err)
+		return serialized
+	}
+
+	decoded, err := codec.Decode(payload)
+	if err != nil {
+		c.logger.Warnf("failed to decode payload for workflow '%s' with codec '%s': %v", instanceID, codecName, err)
+		return serialized
+	}
+
+	if !codec.TextSafe() {
+		return base64.StdEncoding.EncodeToString(decoded)
+	}
+	return string(decoded)
+}
+
+// codecForDecode resolves the Codec named by codecName, looking up the encrypted codec's secret
+// reference from the tracked instance record since Get has no other way to recover it.
+func (c *workflowEngineComponent) codecForDecode(ctx context.Context, instanceID, codecName string) (Codec, error) {
+	if codecName != encryptedCodecName {
+		return codecByName(codecName)
+	}
+
+	_, keyRef := c.trackedCodec(instanceID)
+	if keyRef == "" {
+		return nil, fmt.Errorf("no codec key reference tracked for workflow '%s'", instanceID)
+	}
+	return c.resolveEncryptedCodec(ctx, keyRef)
+}
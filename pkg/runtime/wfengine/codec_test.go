@@ -0,0 +1,162 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package wfengine
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/secretstores"
+	"github.com/dapr/kit/logger"
+)
+
+func TestJSONCodecRoundTrip(t *testing.T) {
+	codec := jsonCodec{}
+	payload := []byte(`{"hello":"world"}`)
+
+	encoded, err := codec.Encode(payload)
+	require.NoError(t, err)
+
+	decoded, err := codec.Decode(encoded)
+	require.NoError(t, err)
+	assert.Equal(t, payload, decoded)
+}
+
+func TestProtobufAnyCodecRoundTrip(t *testing.T) {
+	codec := protobufAnyCodec{}
+	payload := []byte("arbitrary protobuf-marshaled bytes")
+
+	encoded, err := codec.Encode(payload)
+	require.NoError(t, err)
+
+	decoded, err := codec.Decode(encoded)
+	require.NoError(t, err)
+	assert.Equal(t, payload, decoded)
+}
+
+func TestMessagePackCodecRoundTrip(t *testing.T) {
+	codec := messagePackCodec{}
+	payload := []byte(`{"hello":"world","count":3}`)
+
+	encoded, err := codec.Encode(payload)
+	require.NoError(t, err)
+
+	// A true msgpack encoding of a JSON object is never byte-identical to the JSON input; this
+	// guards against regressing back to wrapping the raw JSON bytes as an opaque msgpack "bin"
+	// blob.
+	assert.NotEqual(t, payload, encoded)
+
+	decoded, err := codec.Decode(encoded)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(payload), string(decoded))
+}
+
+func TestMessagePackCodecEncodeRejectsNonJSON(t *testing.T) {
+	codec := messagePackCodec{}
+	_, err := codec.Encode([]byte("not json"))
+	assert.Error(t, err)
+}
+
+func TestCodecTextSafe(t *testing.T) {
+	assert.True(t, jsonCodec{}.TextSafe())
+	assert.True(t, messagePackCodec{}.TextSafe())
+	assert.False(t, protobufAnyCodec{}.TextSafe())
+	assert.True(t, encryptedCodec{}.TextSafe())
+}
+
+func TestDecodePayloadFieldBase64EncodesNonTextSafeCodec(t *testing.T) {
+	c := &workflowEngineComponent{logger: logger.NewLogger("wfengine.test")}
+	c.trackInstance("instance-1", "wf", "protobuf", "")
+
+	payload := []byte{0x00, 0x01, 0xff, 0xfe, 'h', 'i'}
+	encoded, err := (protobufAnyCodec{}).Encode(payload)
+	require.NoError(t, err)
+
+	envelope := encodePayloadEnvelope("protobuf", encoded)
+	serialized, err := json.Marshal(envelope)
+	require.NoError(t, err)
+
+	field := c.decodePayloadField(context.Background(), "instance-1", string(serialized))
+
+	decoded, err := base64.StdEncoding.DecodeString(field)
+	require.NoError(t, err)
+	assert.Equal(t, payload, decoded)
+}
+
+// fakeSecretStoreClient implements SecretStoreClient for tests, serving a single in-memory secret.
+type fakeSecretStoreClient struct {
+	secretName string
+	value      string
+}
+
+func (f *fakeSecretStoreClient) GetSecret(ctx context.Context, req secretstores.GetSecretRequest) (secretstores.GetSecretResponse, error) {
+	if req.Name != f.secretName {
+		return secretstores.GetSecretResponse{}, assert.AnError
+	}
+	return secretstores.GetSecretResponse{Data: map[string]string{f.secretName: f.value}}, nil
+}
+
+func TestEncryptedCodecRoundTrip(t *testing.T) {
+	client := &fakeSecretStoreClient{secretName: "workflow-key", value: "0123456789abcdef0123456789abcdef"[:32]}
+
+	codec, err := newEncryptedCodec(context.Background(), client, "mystore", "workflow-key")
+	require.NoError(t, err)
+
+	payload := []byte(`{"hello":"world"}`)
+	encoded, err := codec.Encode(payload)
+	require.NoError(t, err)
+	assert.NotEqual(t, payload, encoded)
+
+	decoded, err := codec.Decode(encoded)
+	require.NoError(t, err)
+	assert.Equal(t, payload, decoded)
+}
+
+func TestResolveEncryptedCodecUsesNamedStore(t *testing.T) {
+	wantStore := &fakeSecretStoreClient{secretName: "workflow-key", value: "0123456789abcdef0123456789abcdef"[:32]}
+	otherStore := &fakeSecretStoreClient{secretName: "workflow-key", value: "ffffffffffffffffffffffffffffffff"[:32]}
+
+	c := &workflowEngineComponent{
+		secretStores: func(storeName string) (SecretStoreClient, bool) {
+			switch storeName {
+			case "wantstore":
+				return wantStore, true
+			case "otherstore":
+				return otherStore, true
+			default:
+				return nil, false
+			}
+		},
+	}
+
+	codec, err := c.resolveEncryptedCodec(context.Background(), "wantstore/workflow-key")
+	require.NoError(t, err)
+
+	payload := []byte(`{"hello":"world"}`)
+	encoded, err := codec.Encode(payload)
+	require.NoError(t, err)
+
+	decoded, err := codec.Decode(encoded)
+	require.NoError(t, err)
+	assert.Equal(t, payload, decoded)
+
+	_, err = c.resolveEncryptedCodec(context.Background(), "unconfigured/workflow-key")
+	assert.Error(t, err)
+}
@@ -18,6 +18,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/microsoft/durabletask-go/api"
@@ -43,11 +44,33 @@ var ComponentDefinition = componentsV1alpha1.Component{
 	},
 }
 
-func BuiltinWorkflowFactory(engine *WorkflowEngine) func(logger.Logger) workflows.Workflow {
+// SecretStoreResolver looks up the Dapr secret store component registered under storeName, as
+// used by the encrypted payload codec to resolve its AES-GCM key. It's satisfied by the Dapr
+// runtime's secret store component registry.
+type SecretStoreResolver func(storeName string) (SecretStoreClient, bool)
+
+// BuiltinWorkflowFactory builds the workflow.dapr component. secretStores resolves the secret
+// store instances the encrypted payload codec needs; pass nil if the encrypted codec isn't used.
+func BuiltinWorkflowFactory(engine *WorkflowEngine, secretStores SecretStoreResolver) func(logger.Logger) workflows.Workflow {
 	return func(logger logger.Logger) workflows.Workflow {
+		// Not every backend.Backend implementation supports these capabilities yet; the features
+		// that depend on them (GetHistory/span export, List/the TTL reaper, durable schedules)
+		// degrade gracefully when the backend doesn't.
+		historyBackend, _ := engine.backend.(HistoryBackend)
+		instanceEnumerator, _ := engine.backend.(InstanceEnumerator)
+		scheduleStore, _ := engine.backend.(ScheduleStore)
+
+		if secretStores == nil {
+			secretStores = func(string) (SecretStoreClient, bool) { return nil, false }
+		}
+
 		return &workflowEngineComponent{
-			logger: logger,
-			client: backend.NewTaskHubClient(engine.backend),
+			logger:             logger,
+			client:             backend.NewTaskHubClient(engine.backend),
+			historyBackend:     historyBackend,
+			instanceEnumerator: instanceEnumerator,
+			scheduleStore:      scheduleStore,
+			secretStores:       secretStores,
 		}
 	}
 }
@@ -56,10 +79,79 @@ type workflowEngineComponent struct {
 	workflows.Workflow
 	logger logger.Logger
 	client backend.TaskHubClient
+
+	instancesMu        sync.Mutex
+	instances          map[string]*instanceRecord
+	instanceEnumerator InstanceEnumerator
+
+	reaperCancel context.CancelFunc
+
+	defaultCodec Codec
+	secretStores SecretStoreResolver
+
+	scheduleStore   ScheduleStore
+	schedulerCancel context.CancelFunc
+
+	historyBackend HistoryBackend
+	exporterCancel context.CancelFunc
+}
+
+// Close stops the background goroutines started by Init (the TTL reaper, the schedule ticker, and
+// the span exporter), if any were started. It's safe to call even when none of them were.
+func (c *workflowEngineComponent) Close() error {
+	if c.reaperCancel != nil {
+		c.reaperCancel()
+	}
+	if c.schedulerCancel != nil {
+		c.schedulerCancel()
+	}
+	if c.exporterCancel != nil {
+		c.exporterCancel()
+	}
+	return nil
 }
 
 func (c *workflowEngineComponent) Init(metadata workflows.Metadata) error {
 	c.logger.Info("initializing Dapr workflow component")
+
+	codecName := metadata.Properties[payloadCodecMetadataKey]
+	if codecName == encryptedCodecName {
+		codec, err := c.resolveEncryptedCodec(context.Background(), metadata.Properties["dapr.workflow.payload_codec_key"])
+		if err != nil {
+			return fmt.Errorf("failed to configure default payload codec: %w", err)
+		}
+		c.defaultCodec = codec
+	} else {
+		codec, err := codecByName(codecName)
+		if err != nil {
+			return err
+		}
+		c.defaultCodec = codec
+	}
+
+	ttls, err := parseReaperTTLs(metadata.Properties)
+	if err != nil {
+		return err
+	}
+	if ttls.completed > 0 || ttls.failed > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		c.reaperCancel = cancel
+		go c.runReaper(ctx, ttls)
+	}
+
+	if c.scheduleStore == nil {
+		c.scheduleStore = newInMemoryScheduleStore()
+	}
+	schedulerCtx, cancel := context.WithCancel(context.Background())
+	c.schedulerCancel = cancel
+	go c.runScheduler(schedulerCtx)
+
+	if c.historyBackend != nil {
+		exporterCtx, cancel := context.WithCancel(context.Background())
+		c.exporterCancel = cancel
+		go c.runSpanExporter(exporterCtx)
+	}
+
 	return nil
 }
 
@@ -74,9 +166,39 @@ func (c *workflowEngineComponent) Start(ctx context.Context, req *workflows.Star
 		opts = append(opts, api.WithInstanceID(api.InstanceID(req.WorkflowReference.InstanceID)))
 	}
 
-	// Input is also optional. However, inputs are expected to be JSON-serializable.
+	// Input is also optional. However, inputs are expected to be JSON-serializable, unless a
+	// non-default "dapr.workflow.payload_codec" is requested via Options.
+	var codecName, codecKeyRef string
+	var encodedInput []byte
 	if req.Input != nil {
-		opts = append(opts, api.WithInput(req.Input))
+		codec, err := c.resolveCodec(ctx, req.Options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve payload codec: %w", err)
+		}
+
+		encoded, err := codec.Encode(req.Input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode workflow input with codec '%s': %w", codec.Name(), err)
+		}
+
+		encodedInput = encodePayloadEnvelope(codec.Name(), encoded)
+		opts = append(opts, api.WithInput(encodedInput))
+		codecName = codec.Name()
+		if req.Options != nil {
+			codecKeyRef = req.Options["dapr.workflow.payload_codec_key"]
+		}
+	}
+
+	// A "dapr.workflow.schedule" option registers a recurring trigger instead of starting the
+	// workflow immediately; the scheduler fires ScheduleNewOrchestration on each tick.
+	if sched, err := parseScheduleOptions(req.WorkflowName, encodedInput, req.Options, codecName, codecKeyRef); err != nil {
+		return nil, err
+	} else if sched != nil {
+		if err := c.scheduleStore.SaveSchedule(ctx, sched); err != nil {
+			return nil, fmt.Errorf("failed to save schedule for workflow '%s': %w", req.WorkflowName, err)
+		}
+		c.logger.Infof("registered schedule '%s' for workflow '%s'", sched.ID, req.WorkflowName)
+		return &workflows.WorkflowReference{InstanceID: sched.ID}, nil
 	}
 
 	// Start time is also optional and must be in the RFC3339 format (e.g. 2009-11-10T23:00:00Z).
@@ -99,6 +221,7 @@ func (c *workflowEngineComponent) Start(ctx context.Context, req *workflows.Star
 	}
 
 	c.logger.Infof("created new workflow instance with ID '%s'", workflowID)
+	c.trackInstance(string(workflowID), req.WorkflowName, codecName, codecKeyRef)
 	wfRef := &workflows.WorkflowReference{
 		InstanceID: string(workflowID),
 	}
@@ -118,38 +241,170 @@ func (c *workflowEngineComponent) Terminate(ctx context.Context, req *workflows.
 	return nil
 }
 
+func (c *workflowEngineComponent) Suspend(ctx context.Context, req *workflows.WorkflowReference) error {
+	if req.InstanceID == "" {
+		return fmt.Errorf("a workflow instance ID is required")
+	}
+
+	if err := c.client.SuspendOrchestration(ctx, api.InstanceID(req.InstanceID), ""); err != nil {
+		return fmt.Errorf("failed to suspend workflow %s: %w", req.InstanceID, err)
+	}
+
+	c.logger.Infof("suspended workflow instance '%s'", req.InstanceID)
+	return nil
+}
+
+func (c *workflowEngineComponent) Resume(ctx context.Context, req *workflows.WorkflowReference) error {
+	if req.InstanceID == "" {
+		return fmt.Errorf("a workflow instance ID is required")
+	}
+
+	if err := c.client.ResumeOrchestration(ctx, api.InstanceID(req.InstanceID), ""); err != nil {
+		return fmt.Errorf("failed to resume workflow %s: %w", req.InstanceID, err)
+	}
+
+	c.logger.Infof("resumed workflow instance '%s'", req.InstanceID)
+	return nil
+}
+
+// RaiseEvent signals a running workflow instance with an external event. The eventData payload
+// is expected to be JSON-serializable, mirroring the Input handling in Start.
+func (c *workflowEngineComponent) RaiseEvent(ctx context.Context, req *workflows.RaiseEventRequest) error {
+	if req.InstanceID == "" {
+		return fmt.Errorf("a workflow instance ID is required")
+	}
+	if req.EventName == "" {
+		return fmt.Errorf("an event name is required")
+	}
+
+	var opts []api.RaiseEventOptions
+	if req.EventData != nil {
+		opts = append(opts, api.WithEventPayload(req.EventData))
+	}
+
+	if err := c.client.RaiseEvent(ctx, api.InstanceID(req.InstanceID), req.EventName, opts...); err != nil {
+		return fmt.Errorf("failed to raise event '%s' on workflow %s: %w", req.EventName, req.InstanceID, err)
+	}
+
+	c.logger.Infof("raised event '%s' on workflow instance '%s'", req.EventName, req.InstanceID)
+	return nil
+}
+
+// Purge removes the state of a completed workflow instance from the backing store. It fails if
+// the instance is still running.
+func (c *workflowEngineComponent) Purge(ctx context.Context, req *workflows.WorkflowReference) error {
+	if req.InstanceID == "" {
+		return fmt.Errorf("a workflow instance ID is required")
+	}
+
+	if err := c.client.PurgeOrchestrationState(ctx, api.InstanceID(req.InstanceID)); err != nil {
+		return fmt.Errorf("failed to purge workflow %s: %w", req.InstanceID, err)
+	}
+	c.untrackInstance(req.InstanceID)
+
+	c.logger.Infof("purged state for workflow instance '%s'", req.InstanceID)
+	return nil
+}
+
+// WaitForStart blocks until the requested workflow instance has started running, or until the
+// optional "dapr.workflow.wait_timeout" duration (e.g. "30s") elapses.
+func (c *workflowEngineComponent) WaitForStart(ctx context.Context, req *workflows.StartRequest) (*workflows.StateResponse, error) {
+	ctx, cancel, err := withWaitTimeout(ctx, req.Options)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	metadata, err := c.client.WaitForOrchestrationStart(ctx, api.InstanceID(req.WorkflowReference.InstanceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed waiting for workflow %s to start: %w", req.WorkflowReference.InstanceID, err)
+	}
+
+	return c.stateResponseFromMetadata(ctx, req.WorkflowReference.InstanceID, metadata), nil
+}
+
+// WaitForCompletion blocks until the requested workflow instance reaches a terminal state, or
+// until the optional "dapr.workflow.wait_timeout" duration (e.g. "30s") elapses.
+func (c *workflowEngineComponent) WaitForCompletion(ctx context.Context, req *workflows.StartRequest) (*workflows.StateResponse, error) {
+	ctx, cancel, err := withWaitTimeout(ctx, req.Options)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	metadata, err := c.client.WaitForOrchestrationCompletion(ctx, api.InstanceID(req.WorkflowReference.InstanceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed waiting for workflow %s to complete: %w", req.WorkflowReference.InstanceID, err)
+	}
+
+	return c.stateResponseFromMetadata(ctx, req.WorkflowReference.InstanceID, metadata), nil
+}
+
+// withWaitTimeout derives a context bound by the "dapr.workflow.wait_timeout" option, if present.
+func withWaitTimeout(ctx context.Context, opts map[string]string) (context.Context, context.CancelFunc, error) {
+	if opts == nil {
+		return ctx, func() {}, nil
+	}
+
+	raw, ok := opts["dapr.workflow.wait_timeout"]
+	if !ok {
+		return ctx, func() {}, nil
+	}
+
+	timeout, err := time.ParseDuration(raw)
+	if err != nil {
+		return nil, nil, fmt.Errorf("dapr.workflow.wait_timeout must be a valid duration (e.g. \"30s\"): %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, cancel, nil
+}
+
 func (c *workflowEngineComponent) Get(ctx context.Context, req *workflows.WorkflowReference) (*workflows.StateResponse, error) {
 	if req.InstanceID == "" {
 		return nil, fmt.Errorf("a workflow instance ID is required")
 	}
 
-	if metadata, err := c.client.FetchOrchestrationMetadata(ctx, api.InstanceID(req.InstanceID)); err != nil {
+	metadata, err := c.client.FetchOrchestrationMetadata(ctx, api.InstanceID(req.InstanceID))
+	if err != nil {
 		return nil, fmt.Errorf("failed to get workflow metadata for '%s': %w", req.InstanceID, err)
-	} else {
-		res := &workflows.StateResponse{
-			WFInfo: workflows.WorkflowReference{
-				InstanceID: req.InstanceID,
-			},
-			StartTime: metadata.CreatedAt.Format(time.RFC3339),
-			Metadata: map[string]string{
-				"dapr.workflow.name":           metadata.Name,
-				"dapr.workflow.runtime_status": getStatusString(int32(metadata.RuntimeStatus)),
-				"dapr.workflow.input":          metadata.SerializedInput,
-				"dapr.workflow.custom_status":  metadata.SerializedCustomStatus,
-				"dapr.workflow.last_updated":   metadata.LastUpdatedAt.Format(time.RFC3339),
-			},
-		}
+	}
 
-		// Status-specific fields
-		if metadata.FailureDetails != nil {
-			res.Metadata["dapr.workflow.failure.error_type"] = metadata.FailureDetails.ErrorType
-			res.Metadata["dapr.workflow.failure.error_message"] = metadata.FailureDetails.ErrorMessage
-		} else if metadata.IsComplete() {
-			res.Metadata["dapr.workflow.output"] = metadata.SerializedOutput
-		}
+	return c.stateResponseFromMetadata(ctx, req.InstanceID, metadata), nil
+}
+
+// stateResponseFromMetadata converts durabletask-go orchestration metadata into the StateResponse
+// shape shared by Get, WaitForStart, WaitForCompletion, and List. If the instance's input/output
+// was written with a non-default payload codec (tracked at Start time), it's decoded back here
+// and the codec identifier is surfaced in the "dapr.workflow.payload_codec" metadata key.
+func (c *workflowEngineComponent) stateResponseFromMetadata(ctx context.Context, instanceID string, metadata *api.OrchestrationMetadata) *workflows.StateResponse {
+	res := &workflows.StateResponse{
+		WFInfo: workflows.WorkflowReference{
+			InstanceID: instanceID,
+		},
+		StartTime: metadata.CreatedAt.Format(time.RFC3339),
+		Metadata: map[string]string{
+			"dapr.workflow.name":           metadata.Name,
+			"dapr.workflow.runtime_status": getStatusString(int32(metadata.RuntimeStatus)),
+			"dapr.workflow.input":          c.decodePayloadField(ctx, instanceID, metadata.SerializedInput),
+			"dapr.workflow.custom_status":  metadata.SerializedCustomStatus,
+			"dapr.workflow.last_updated":   metadata.LastUpdatedAt.Format(time.RFC3339),
+		},
+	}
 
-		return res, nil
+	// Status-specific fields
+	if metadata.FailureDetails != nil {
+		res.Metadata["dapr.workflow.failure.error_type"] = metadata.FailureDetails.ErrorType
+		res.Metadata["dapr.workflow.failure.error_message"] = metadata.FailureDetails.ErrorMessage
+	} else if metadata.IsComplete() {
+		res.Metadata["dapr.workflow.output"] = c.decodePayloadField(ctx, instanceID, metadata.SerializedOutput)
 	}
+
+	if codecName, _ := c.trackedCodec(instanceID); codecName != "" {
+		res.Metadata[payloadCodecMetadataKey] = codecName
+	}
+
+	return res
 }
 
 // Status values are defined at: https://github.com/microsoft/durabletask-go/blob/119b361079c45e368f83b223888d56a436ac59b9/internal/protos/orchestrator_service.pb.go#L42-L64
@@ -174,4 +429,4 @@ func getStatusString(status int32) string {
 	default:
 		return "UNKNOWN"
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package wfengine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/workflows"
+)
+
+func TestSuspendRequiresInstanceID(t *testing.T) {
+	c := &workflowEngineComponent{}
+	err := c.Suspend(context.Background(), &workflows.WorkflowReference{})
+	assert.Error(t, err)
+}
+
+func TestResumeRequiresInstanceID(t *testing.T) {
+	c := &workflowEngineComponent{}
+	err := c.Resume(context.Background(), &workflows.WorkflowReference{})
+	assert.Error(t, err)
+}
+
+func TestTerminateRequiresInstanceID(t *testing.T) {
+	c := &workflowEngineComponent{}
+	err := c.Terminate(context.Background(), &workflows.WorkflowReference{})
+	assert.Error(t, err)
+}
+
+func TestPurgeRequiresInstanceID(t *testing.T) {
+	c := &workflowEngineComponent{}
+	err := c.Purge(context.Background(), &workflows.WorkflowReference{})
+	assert.Error(t, err)
+}
+
+func TestGetRequiresInstanceID(t *testing.T) {
+	c := &workflowEngineComponent{}
+	_, err := c.Get(context.Background(), &workflows.WorkflowReference{})
+	assert.Error(t, err)
+}
+
+func TestRaiseEventRequiresInstanceID(t *testing.T) {
+	c := &workflowEngineComponent{}
+	err := c.RaiseEvent(context.Background(), &workflows.RaiseEventRequest{EventName: "evt"})
+	assert.Error(t, err)
+}
+
+func TestRaiseEventRequiresEventName(t *testing.T) {
+	c := &workflowEngineComponent{}
+	err := c.RaiseEvent(context.Background(), &workflows.RaiseEventRequest{InstanceID: "instance-1"})
+	assert.Error(t, err)
+}
+
+func TestWithWaitTimeoutNoOptions(t *testing.T) {
+	ctx, cancel, err := withWaitTimeout(context.Background(), nil)
+	defer cancel()
+	require.NoError(t, err)
+	_, hasDeadline := ctx.Deadline()
+	assert.False(t, hasDeadline)
+}
+
+func TestWithWaitTimeoutParsesDuration(t *testing.T) {
+	ctx, cancel, err := withWaitTimeout(context.Background(), map[string]string{"dapr.workflow.wait_timeout": "30s"})
+	defer cancel()
+	require.NoError(t, err)
+	_, hasDeadline := ctx.Deadline()
+	assert.True(t, hasDeadline)
+}
+
+func TestWithWaitTimeoutRejectsInvalidDuration(t *testing.T) {
+	_, _, err := withWaitTimeout(context.Background(), map[string]string{"dapr.workflow.wait_timeout": "not-a-duration"})
+	assert.Error(t, err)
+}
+
+func TestGetStatusString(t *testing.T) {
+	want := map[int32]string{
+		0: "RUNNING", 1: "COMPLETED", 2: "CONTINUED_AS_NEW", 3: "FAILED",
+		4: "CANCELED", 5: "TERMINATED", 6: "PENDING", 7: "SUSPENDED",
+	}
+	for code, status := range want {
+		assert.Equal(t, status, getStatusString(code))
+	}
+	assert.Equal(t, "UNKNOWN", getStatusString(99))
+}
+
+func TestCloseIsSafeWithNoBackgroundGoroutinesStarted(t *testing.T) {
+	c := &workflowEngineComponent{}
+	assert.NoError(t, c.Close())
+}
+
+func TestCloseCancelsAllStartedContexts(t *testing.T) {
+	var reaperCanceled, schedulerCanceled, exporterCanceled bool
+	c := &workflowEngineComponent{
+		reaperCancel:    func() { reaperCanceled = true },
+		schedulerCancel: func() { schedulerCanceled = true },
+		exporterCancel:  func() { exporterCanceled = true },
+	}
+
+	require.NoError(t, c.Close())
+
+	assert.True(t, reaperCanceled)
+	assert.True(t, schedulerCanceled)
+	assert.True(t, exporterCanceled)
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package wfengine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/microsoft/durabletask-go/api"
+	"github.com/microsoft/durabletask-go/backend"
+
+	"github.com/dapr/components-contrib/workflows"
+)
+
+// HistoryBackend is the subset of durabletask-go's backend.Backend this component needs to
+// retrieve the raw event history for an instance, independent of the higher-level TaskHubClient
+// used for the rest of workflowEngineComponent.
+type HistoryBackend interface {
+	GetHistory(ctx context.Context, instanceID api.InstanceID) ([]*backend.HistoryEvent, error)
+}
+
+// GetHistory returns the full, ordered event history for a workflow instance. Unlike Get, which
+// surfaces a flattened snapshot, this preserves each individual TaskScheduled/TaskCompleted/
+// TimerCreated/SubOrchestrationInstanceCreated/EventRaised event so callers can reconstruct the
+// exact execution timeline.
+func (c *workflowEngineComponent) GetHistory(ctx context.Context, req *workflows.WorkflowReference, opts *workflows.HistoryOptions) ([]workflows.HistoryEvent, error) {
+	if req.InstanceID == "" {
+		return nil, fmt.Errorf("a workflow instance ID is required")
+	}
+	if c.historyBackend == nil {
+		return nil, fmt.Errorf("history retrieval is not supported by the configured backend")
+	}
+
+	rawEvents, err := c.historyBackend.GetHistory(ctx, api.InstanceID(req.InstanceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history for workflow '%s': %w", req.InstanceID, err)
+	}
+
+	events := make([]workflows.HistoryEvent, 0, len(rawEvents))
+	for _, e := range rawEvents {
+		events = append(events, historyEventFromProto(e))
+	}
+	return filterHistoryEvents(events, opts), nil
+}
+
+// filterHistoryEvents applies a HistoryOptions filter to an already-mapped event list: Since
+// restricts to events at or after that time, EventTypes restricts to those event types, and Limit
+// caps the number of events returned. A nil opts returns events unchanged.
+func filterHistoryEvents(events []workflows.HistoryEvent, opts *workflows.HistoryOptions) []workflows.HistoryEvent {
+	if opts == nil {
+		return events
+	}
+
+	eventTypes := make(map[string]bool, len(opts.EventTypes))
+	for _, t := range opts.EventTypes {
+		eventTypes[t] = true
+	}
+
+	filtered := make([]workflows.HistoryEvent, 0, len(events))
+	for _, he := range events {
+		if opts.Since != nil {
+			if ts, err := time.Parse(time.RFC3339Nano, he.Timestamp); err == nil && ts.Before(*opts.Since) {
+				continue
+			}
+		}
+		if len(eventTypes) > 0 && !eventTypes[he.EventType] {
+			continue
+		}
+
+		filtered = append(filtered, he)
+
+		if opts.Limit > 0 && len(filtered) >= opts.Limit {
+			break
+		}
+	}
+	return filtered
+}
+
+// historyEventFromProto converts a durabletask-go history event into the typed, Dapr-facing
+// workflows.HistoryEvent shape.
+func historyEventFromProto(e *backend.HistoryEvent) workflows.HistoryEvent {
+	he := workflows.HistoryEvent{
+		EventID:   e.GetEventId(),
+		Timestamp: e.GetTimestamp().AsTime().Format(time.RFC3339Nano),
+	}
+
+	switch {
+	case e.GetExecutionStarted() != nil:
+		he.EventType = "ExecutionStarted"
+		he.Name = e.GetExecutionStarted().GetName()
+	case e.GetExecutionCompleted() != nil:
+		he.EventType = "ExecutionCompleted"
+	case e.GetTaskScheduled() != nil:
+		he.EventType = "TaskScheduled"
+		he.Name = e.GetTaskScheduled().GetName()
+	case e.GetTaskCompleted() != nil:
+		he.EventType = "TaskCompleted"
+		he.RelatedEventID = e.GetTaskCompleted().GetTaskScheduledId()
+	case e.GetTaskFailed() != nil:
+		he.EventType = "TaskFailed"
+		he.RelatedEventID = e.GetTaskFailed().GetTaskScheduledId()
+	case e.GetSubOrchestrationInstanceCreated() != nil:
+		he.EventType = "SubOrchestrationInstanceCreated"
+		he.Name = e.GetSubOrchestrationInstanceCreated().GetName()
+		he.RelatedInstanceID = e.GetSubOrchestrationInstanceCreated().GetInstanceId()
+	case e.GetSubOrchestrationInstanceCompleted() != nil:
+		he.EventType = "SubOrchestrationInstanceCompleted"
+		he.RelatedEventID = e.GetSubOrchestrationInstanceCompleted().GetTaskScheduledId()
+	case e.GetSubOrchestrationInstanceFailed() != nil:
+		he.EventType = "SubOrchestrationInstanceFailed"
+		he.RelatedEventID = e.GetSubOrchestrationInstanceFailed().GetTaskScheduledId()
+	case e.GetTimerCreated() != nil:
+		he.EventType = "TimerCreated"
+	case e.GetTimerFired() != nil:
+		he.EventType = "TimerFired"
+		he.RelatedEventID = e.GetTimerFired().GetTimerId()
+	case e.GetEventRaised() != nil:
+		he.EventType = "EventRaised"
+		he.Name = e.GetEventRaised().GetName()
+	default:
+		he.EventType = "Unknown"
+	}
+
+	return he
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package wfengine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/workflows"
+)
+
+func TestFilterHistoryEventsNilOptsReturnsAllUnchanged(t *testing.T) {
+	events := []workflows.HistoryEvent{{EventID: 1, EventType: "ExecutionStarted"}}
+	assert.Equal(t, events, filterHistoryEvents(events, nil))
+}
+
+func TestFilterHistoryEventsByEventType(t *testing.T) {
+	events := []workflows.HistoryEvent{
+		{EventID: 1, EventType: "ExecutionStarted"},
+		{EventID: 2, EventType: "TaskScheduled"},
+		{EventID: 3, EventType: "TaskCompleted"},
+	}
+
+	filtered := filterHistoryEvents(events, &workflows.HistoryOptions{EventTypes: []string{"TaskScheduled", "TaskCompleted"}})
+	require.Len(t, filtered, 2)
+	assert.Equal(t, int32(2), filtered[0].EventID)
+	assert.Equal(t, int32(3), filtered[1].EventID)
+}
+
+func TestFilterHistoryEventsBySince(t *testing.T) {
+	older := time.Now().Add(-time.Hour)
+	newer := time.Now()
+
+	events := []workflows.HistoryEvent{
+		{EventID: 1, Timestamp: older.Format(time.RFC3339Nano)},
+		{EventID: 2, Timestamp: newer.Format(time.RFC3339Nano)},
+	}
+
+	cutoff := older.Add(time.Minute)
+	filtered := filterHistoryEvents(events, &workflows.HistoryOptions{Since: &cutoff})
+	require.Len(t, filtered, 1)
+	assert.Equal(t, int32(2), filtered[0].EventID)
+}
+
+func TestFilterHistoryEventsByLimit(t *testing.T) {
+	events := []workflows.HistoryEvent{
+		{EventID: 1}, {EventID: 2}, {EventID: 3},
+	}
+
+	filtered := filterHistoryEvents(events, &workflows.HistoryOptions{Limit: 2})
+	require.Len(t, filtered, 2)
+	assert.Equal(t, int32(1), filtered[0].EventID)
+	assert.Equal(t, int32(2), filtered[1].EventID)
+}
+
+func TestGetHistoryRequiresInstanceID(t *testing.T) {
+	c := &workflowEngineComponent{}
+	_, err := c.GetHistory(context.Background(), &workflows.WorkflowReference{}, nil)
+	assert.Error(t, err)
+}
+
+func TestGetHistoryRequiresHistoryBackend(t *testing.T) {
+	c := &workflowEngineComponent{}
+	_, err := c.GetHistory(context.Background(), &workflows.WorkflowReference{InstanceID: "instance-1"}, nil)
+	assert.Error(t, err)
+}
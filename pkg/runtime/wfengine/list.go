@@ -0,0 +1,241 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package wfengine
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/microsoft/durabletask-go/api"
+
+	"github.com/dapr/components-contrib/workflows"
+)
+
+// instanceRecord is the minimal bookkeeping List/the reaper fall back on when no
+// InstanceEnumerator-capable backend is configured. It's populated as instances are started and
+// trimmed by the TTL reaper once an instance is purged. This index is process-local: it's lost on
+// restart and never sees instances started by another replica, so candidateInstanceIDs prefers
+// instanceEnumerator whenever one is available.
+type instanceRecord struct {
+	instanceID   string
+	workflowName string
+	createdAt    time.Time
+	codecName    string
+	codecKeyRef  string
+}
+
+// InstanceEnumerator is implemented by durabletask-go backends that can enumerate the instance
+// IDs they hold, independent of any particular component process's lifetime. This is what lets
+// List and the TTL reaper see instances created by other replicas or by sub-orchestrations.
+type InstanceEnumerator interface {
+	ListInstanceIDs(ctx context.Context) ([]string, error)
+}
+
+// candidateInstanceIDs returns every instance ID List/the reaper should consider. When the
+// backend supports InstanceEnumerator, its answer is authoritative and durable. Otherwise this
+// falls back to the process-local instanceRecord index, which only covers instances this
+// component process itself has started or scheduled.
+func (c *workflowEngineComponent) candidateInstanceIDs(ctx context.Context) ([]string, error) {
+	if c.instanceEnumerator != nil {
+		ids, err := c.instanceEnumerator.ListInstanceIDs(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to enumerate workflow instances: %w", err)
+		}
+		return ids, nil
+	}
+
+	c.logger.Warn("list: no backend InstanceEnumerator configured; falling back to this replica's " +
+		"in-process instance index, which is lost on restart and excludes instances from other replicas")
+	records := c.snapshotInstances()
+	ids := make([]string, len(records))
+	for i, rec := range records {
+		ids[i] = rec.instanceID
+	}
+	return ids, nil
+}
+
+// listCandidate pairs an instance ID with its fetched metadata for sorting/filtering in List.
+type listCandidate struct {
+	instanceID string
+	metadata   *api.OrchestrationMetadata
+}
+
+// listCursor is the decoded form of a ListRequest/ListResponse ContinuationToken: the sort key of
+// the last instance returned on the previous page. Paging resumes strictly after this key, so
+// instances created or purged between calls can't shift already-issued results the way a
+// positional offset would.
+type listCursor struct {
+	createdAtNano int64
+	instanceID    string
+}
+
+// List enumerates known workflow instances, optionally filtered by name, RuntimeStatus,
+// creation time, and last-updated time. Results are ordered by (creation time, instance ID) and
+// paged via an opaque ContinuationToken that encodes a stable cursor rather than a position.
+func (c *workflowEngineComponent) List(ctx context.Context, req *workflows.ListRequest) (*workflows.ListResponse, error) {
+	ids, err := c.candidateInstanceIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]listCandidate, 0, len(ids))
+	for _, id := range ids {
+		metadata, err := c.client.FetchOrchestrationMetadata(ctx, api.InstanceID(id))
+		if err != nil {
+			c.logger.Warnf("list: failed to fetch metadata for workflow '%s': %v", id, err)
+			continue
+		}
+		candidates = append(candidates, listCandidate{instanceID: id, metadata: metadata})
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if !candidates[i].metadata.CreatedAt.Equal(candidates[j].metadata.CreatedAt) {
+			return candidates[i].metadata.CreatedAt.Before(candidates[j].metadata.CreatedAt)
+		}
+		return candidates[i].instanceID < candidates[j].instanceID
+	})
+
+	cursor, err := parseContinuationToken(req.ContinuationToken)
+	if err != nil {
+		return nil, err
+	}
+	startIdx := 0
+	if cursor != nil {
+		startIdx = sort.Search(len(candidates), func(i int) bool {
+			return isAfterCursor(candidates[i], *cursor)
+		})
+	}
+
+	pageSize := req.PageSize
+	if pageSize <= 0 {
+		pageSize = 100
+	}
+
+	statusFilter := make(map[string]bool, len(req.RuntimeStatus))
+	for _, s := range req.RuntimeStatus {
+		statusFilter[s] = true
+	}
+
+	res := &workflows.ListResponse{}
+	lastConsidered := -1
+	for i := startIdx; i < len(candidates) && len(res.Instances) < pageSize; i++ {
+		cand := candidates[i]
+		lastConsidered = i
+		metadata := cand.metadata
+
+		if req.WorkflowName != "" && metadata.Name != req.WorkflowName {
+			continue
+		}
+		if req.CreatedTimeFrom != nil && metadata.CreatedAt.Before(*req.CreatedTimeFrom) {
+			continue
+		}
+		if req.CreatedTimeTo != nil && metadata.CreatedAt.After(*req.CreatedTimeTo) {
+			continue
+		}
+		if len(statusFilter) > 0 && !statusFilter[getStatusString(int32(metadata.RuntimeStatus))] {
+			continue
+		}
+		if req.LastUpdatedFrom != nil && metadata.LastUpdatedAt.Before(*req.LastUpdatedFrom) {
+			continue
+		}
+		if req.LastUpdatedTo != nil && metadata.LastUpdatedAt.After(*req.LastUpdatedTo) {
+			continue
+		}
+
+		res.Instances = append(res.Instances, c.stateResponseFromMetadata(ctx, cand.instanceID, metadata))
+	}
+
+	if lastConsidered >= 0 && lastConsidered < len(candidates)-1 {
+		res.ContinuationToken = encodeContinuationToken(candidates[lastConsidered])
+	}
+
+	return res, nil
+}
+
+// isAfterCursor reports whether candidate sorts strictly after cursor under List's (createdAt,
+// instanceID) ordering.
+func isAfterCursor(candidate listCandidate, cursor listCursor) bool {
+	createdAtNano := candidate.metadata.CreatedAt.UnixNano()
+	if createdAtNano != cursor.createdAtNano {
+		return createdAtNano > cursor.createdAtNano
+	}
+	return candidate.instanceID > cursor.instanceID
+}
+
+func encodeContinuationToken(candidate listCandidate) string {
+	return fmt.Sprintf("%d:%s", candidate.metadata.CreatedAt.UnixNano(), candidate.instanceID)
+}
+
+func parseContinuationToken(token string) (*listCursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+	nanoPart, idPart, ok := strings.Cut(token, ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid continuation token '%s'", token)
+	}
+	nano, err := strconv.ParseInt(nanoPart, 10, 64)
+	if err != nil || idPart == "" {
+		return nil, fmt.Errorf("invalid continuation token '%s'", token)
+	}
+	return &listCursor{createdAtNano: nano, instanceID: idPart}, nil
+}
+
+func (c *workflowEngineComponent) trackInstance(instanceID, workflowName, codecName, codecKeyRef string) {
+	c.instancesMu.Lock()
+	defer c.instancesMu.Unlock()
+	if c.instances == nil {
+		c.instances = make(map[string]*instanceRecord)
+	}
+	c.instances[instanceID] = &instanceRecord{
+		instanceID:   instanceID,
+		workflowName: workflowName,
+		createdAt:    time.Now(),
+		codecName:    codecName,
+		codecKeyRef:  codecKeyRef,
+	}
+}
+
+func (c *workflowEngineComponent) untrackInstance(instanceID string) {
+	c.instancesMu.Lock()
+	defer c.instancesMu.Unlock()
+	delete(c.instances, instanceID)
+}
+
+// trackedCodec returns the codec name and key reference recorded for instanceID at Start time,
+// or two empty strings if the instance isn't tracked (e.g. it was started before this component
+// process came up).
+func (c *workflowEngineComponent) trackedCodec(instanceID string) (codecName, codecKeyRef string) {
+	c.instancesMu.Lock()
+	defer c.instancesMu.Unlock()
+	if rec, ok := c.instances[instanceID]; ok {
+		return rec.codecName, rec.codecKeyRef
+	}
+	return "", ""
+}
+
+func (c *workflowEngineComponent) snapshotInstances() []*instanceRecord {
+	c.instancesMu.Lock()
+	defer c.instancesMu.Unlock()
+	records := make([]*instanceRecord, 0, len(c.instances))
+	for _, rec := range c.instances {
+		records = append(records, rec)
+	}
+	return records
+}
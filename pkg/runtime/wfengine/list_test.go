@@ -0,0 +1,84 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package wfengine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/microsoft/durabletask-go/api"
+)
+
+func TestParseContinuationTokenEmpty(t *testing.T) {
+	cursor, err := parseContinuationToken("")
+	require.NoError(t, err)
+	assert.Nil(t, cursor)
+}
+
+func TestParseContinuationTokenInvalid(t *testing.T) {
+	for _, token := range []string{"no-colon", ":missing-nano", "not-a-number:instance", "123:"} {
+		_, err := parseContinuationToken(token)
+		assert.Error(t, err, "token %q", token)
+	}
+}
+
+func TestContinuationTokenRoundTrip(t *testing.T) {
+	createdAt := time.Now()
+	candidate := listCandidate{
+		instanceID: "instance-42",
+		metadata:   &api.OrchestrationMetadata{CreatedAt: createdAt},
+	}
+
+	token := encodeContinuationToken(candidate)
+	cursor, err := parseContinuationToken(token)
+	require.NoError(t, err)
+	require.NotNil(t, cursor)
+	assert.Equal(t, createdAt.UnixNano(), cursor.createdAtNano)
+	assert.Equal(t, "instance-42", cursor.instanceID)
+}
+
+func TestIsAfterCursorOrdersByCreatedAtThenInstanceID(t *testing.T) {
+	base := time.Now()
+	cursor := listCursor{createdAtNano: base.UnixNano(), instanceID: "b"}
+
+	later := listCandidate{instanceID: "a", metadata: &api.OrchestrationMetadata{CreatedAt: base.Add(time.Second)}}
+	assert.True(t, isAfterCursor(later, cursor))
+
+	earlier := listCandidate{instanceID: "z", metadata: &api.OrchestrationMetadata{CreatedAt: base.Add(-time.Second)}}
+	assert.False(t, isAfterCursor(earlier, cursor))
+
+	sameTimeAfterID := listCandidate{instanceID: "c", metadata: &api.OrchestrationMetadata{CreatedAt: base}}
+	assert.True(t, isAfterCursor(sameTimeAfterID, cursor))
+
+	sameTimeBeforeID := listCandidate{instanceID: "a", metadata: &api.OrchestrationMetadata{CreatedAt: base}}
+	assert.False(t, isAfterCursor(sameTimeBeforeID, cursor))
+}
+
+func TestTrackInstanceAndUntrackInstance(t *testing.T) {
+	c := &workflowEngineComponent{}
+	c.trackInstance("instance-1", "wf", "protobuf", "keyref")
+
+	codecName, codecKeyRef := c.trackedCodec("instance-1")
+	assert.Equal(t, "protobuf", codecName)
+	assert.Equal(t, "keyref", codecKeyRef)
+
+	c.untrackInstance("instance-1")
+	codecName, codecKeyRef = c.trackedCodec("instance-1")
+	assert.Empty(t, codecName)
+	assert.Empty(t, codecKeyRef)
+}
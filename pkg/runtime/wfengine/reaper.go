@@ -0,0 +1,109 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package wfengine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/microsoft/durabletask-go/api"
+)
+
+// reaperInterval is how often the TTL reaper sweeps tracked instances for expired state,
+// analogous to the sweep interval of Argo's ttlcontroller.
+const reaperInterval = 1 * time.Minute
+
+type reaperTTLs struct {
+	completed time.Duration
+	failed    time.Duration
+}
+
+// parseReaperTTLs reads the dapr.workflow.completed_ttl and dapr.workflow.failed_ttl component
+// metadata properties. Either may be omitted to disable TTL-based purging for that outcome.
+func parseReaperTTLs(properties map[string]string) (reaperTTLs, error) {
+	var ttls reaperTTLs
+
+	if raw, ok := properties["dapr.workflow.completed_ttl"]; ok {
+		ttl, err := time.ParseDuration(raw)
+		if err != nil {
+			return ttls, fmt.Errorf("dapr.workflow.completed_ttl must be a valid duration (e.g. \"24h\"): %w", err)
+		}
+		ttls.completed = ttl
+	}
+
+	if raw, ok := properties["dapr.workflow.failed_ttl"]; ok {
+		ttl, err := time.ParseDuration(raw)
+		if err != nil {
+			return ttls, fmt.Errorf("dapr.workflow.failed_ttl must be a valid duration (e.g. \"24h\"): %w", err)
+		}
+		ttls.failed = ttl
+	}
+
+	return ttls, nil
+}
+
+// runReaper periodically purges tracked instances that have been in a terminal state for longer
+// than their configured TTL. It exits when ctx is canceled, which happens when the component is
+// closed.
+func (c *workflowEngineComponent) runReaper(ctx context.Context, ttls reaperTTLs) {
+	ticker := time.NewTicker(reaperInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.reapExpiredInstances(ctx, ttls)
+		}
+	}
+}
+
+func (c *workflowEngineComponent) reapExpiredInstances(ctx context.Context, ttls reaperTTLs) {
+	ids, err := c.candidateInstanceIDs(ctx)
+	if err != nil {
+		c.logger.Warnf("reaper: failed to enumerate workflow instances: %v", err)
+		return
+	}
+
+	for _, instanceID := range ids {
+		metadata, err := c.client.FetchOrchestrationMetadata(ctx, api.InstanceID(instanceID))
+		if err != nil {
+			c.logger.Warnf("reaper: failed to fetch metadata for workflow '%s': %v", instanceID, err)
+			continue
+		}
+		if !metadata.IsComplete() {
+			continue
+		}
+
+		var ttl time.Duration
+		if metadata.FailureDetails != nil {
+			ttl = ttls.failed
+		} else {
+			ttl = ttls.completed
+		}
+		if ttl <= 0 || time.Since(metadata.LastUpdatedAt) < ttl {
+			continue
+		}
+
+		if err := c.client.PurgeOrchestrationState(ctx, api.InstanceID(instanceID)); err != nil {
+			c.logger.Warnf("reaper: failed to purge expired workflow '%s': %v", instanceID, err)
+			continue
+		}
+		c.untrackInstance(instanceID)
+		c.logger.Infof("reaper: purged expired workflow instance '%s'", instanceID)
+	}
+}
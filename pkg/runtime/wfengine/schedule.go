@@ -0,0 +1,345 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package wfengine
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"github.com/microsoft/durabletask-go/api"
+
+	"github.com/dapr/components-contrib/workflows"
+)
+
+// missedFiringPolicy controls what a Schedule does with tick(s) it couldn't run on time, e.g.
+// because the component was down. It mirrors Kubernetes CronJob's concurrencyPolicy-adjacent
+// startingDeadlineSeconds behavior.
+type missedFiringPolicy string
+
+const (
+	missedFiringSkip     missedFiringPolicy = "skip"
+	missedFiringCatchUp  missedFiringPolicy = "catch_up"
+	scheduleTickInterval                    = 1 * time.Second
+)
+
+// Schedule is a recurring trigger for a workflow, created via the "dapr.workflow.schedule"
+// Start option and managed afterwards with ListSchedules/PauseSchedule/DeleteSchedule.
+//
+// paused and nextRun are read and written from both the scheduler goroutine (fireDueSchedules)
+// and whatever goroutine handles PauseSchedule/DeleteSchedule requests, so they're guarded by mu
+// rather than accessed as plain fields.
+type Schedule struct {
+	ID             string
+	WorkflowName   string
+	Input          []byte
+	CronExpression string
+	Timezone       string
+	EndTime        *time.Time
+	MissedFiring   missedFiringPolicy
+
+	// CodecName and CodecKeyRef record the payload codec Input was encoded with at registration
+	// time, mirroring the bookkeeping Start does via trackInstance, so fireSchedule can pass them
+	// through and a later Get on a fired instance decodes Input/Output correctly.
+	CodecName   string
+	CodecKeyRef string
+
+	schedule cron.Schedule
+
+	mu      sync.Mutex
+	paused  bool
+	nextRun time.Time
+}
+
+// Rehydrate lazily populates the internal cron.Schedule representation from CronExpression and
+// Timezone if it isn't already set. parseScheduleOptions sets it directly when a Schedule is
+// created, but a ScheduleStore backed by a real durabletask-go backend only persists Schedule's
+// exported fields, so it must call Rehydrate on every *Schedule it builds from its own rows before
+// returning it from LoadSchedules - otherwise dueFireTimes/fireDueSchedules's sched.schedule.Next
+// calls panic on its nil zero value.
+func (s *Schedule) Rehydrate() error {
+	if s.schedule != nil {
+		return nil
+	}
+	parsed, err := parseCronSchedule(s.CronExpression, s.Timezone)
+	if err != nil {
+		return err
+	}
+	s.schedule = parsed
+	return nil
+}
+
+// IsPaused reports whether the schedule is currently paused.
+func (s *Schedule) IsPaused() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.paused
+}
+
+// SetPaused updates whether the schedule is paused.
+func (s *Schedule) SetPaused(paused bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paused = paused
+}
+
+// NextRun returns the time the schedule is next due to fire.
+func (s *Schedule) NextRun() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.nextRun
+}
+
+// SetNextRun updates the time the schedule is next due to fire.
+func (s *Schedule) SetNextRun(t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextRun = t
+}
+
+// dueFireTimes returns the tick(s) fireDueSchedules should fire for a schedule whose nextRun has
+// come due, given that it was last checked at nextRun and is now being checked at now. Under
+// missedFiringSkip this is always just nextRun itself; under missedFiringCatchUp it also includes
+// every subsequent tick the schedule missed in between, e.g. because the component was down.
+func (s *Schedule) dueFireTimes(nextRun, now time.Time) []time.Time {
+	fireTimes := []time.Time{nextRun}
+	if s.MissedFiring == missedFiringCatchUp {
+		for t := s.schedule.Next(nextRun); !t.After(now); t = s.schedule.Next(t) {
+			fireTimes = append(fireTimes, t)
+		}
+	}
+	return fireTimes
+}
+
+// ScheduleStore persists Schedules. When the configured durabletask-go backend implements this
+// interface, BuiltinWorkflowFactory wires it in directly (mirroring the HistoryBackend/
+// InstanceEnumerator pattern) so schedules survive a component restart; otherwise Init falls back
+// to inMemoryScheduleStore, which does not.
+type ScheduleStore interface {
+	SaveSchedule(ctx context.Context, s *Schedule) error
+	LoadSchedules(ctx context.Context) ([]*Schedule, error)
+	DeleteSchedule(ctx context.Context, id string) error
+}
+
+// inMemoryScheduleStore is the default ScheduleStore used when the component isn't wired to a
+// durable actor-backed store. Schedules are lost on restart, same as the in-process instance
+// index used by List.
+type inMemoryScheduleStore struct {
+	mu        sync.Mutex
+	schedules map[string]*Schedule
+}
+
+func newInMemoryScheduleStore() *inMemoryScheduleStore {
+	return &inMemoryScheduleStore{schedules: make(map[string]*Schedule)}
+}
+
+func (s *inMemoryScheduleStore) SaveSchedule(ctx context.Context, sched *Schedule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.schedules[sched.ID] = sched
+	return nil
+}
+
+func (s *inMemoryScheduleStore) LoadSchedules(ctx context.Context) ([]*Schedule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]*Schedule, 0, len(s.schedules))
+	for _, sched := range s.schedules {
+		out = append(out, sched)
+	}
+	return out, nil
+}
+
+func (s *inMemoryScheduleStore) DeleteSchedule(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.schedules, id)
+	return nil
+}
+
+// parseCronSchedule parses a cron expression into the internal cron.Schedule representation
+// Schedule needs to compute fire times. It's shared by parseScheduleOptions and Rehydrate so both
+// paths that can produce a *Schedule agree on parser options. A non-empty timezone pins the
+// schedule's fire times to that location; an empty one uses the process-local time zone.
+func parseCronSchedule(expr, timezone string) (cron.Schedule, error) {
+	parser := cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+	spec := expr
+	if timezone != "" {
+		spec = "CRON_TZ=" + timezone + " " + expr
+	}
+	parsed, err := parser.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron expression '%s': %w", expr, err)
+	}
+	return parsed, nil
+}
+
+// parseScheduleOptions reads the "dapr.workflow.schedule" family of Start options, returning a
+// nil Schedule (and nil error) when no schedule was requested. codecName/codecKeyRef are the
+// payload codec Input was already encoded with, if any, so fireSchedule can decode fired
+// instances' input/output the same way Start's trackInstance does.
+func parseScheduleOptions(workflowName string, input []byte, opts map[string]string, codecName, codecKeyRef string) (*Schedule, error) {
+	if opts == nil {
+		return nil, nil
+	}
+	expr, ok := opts["dapr.workflow.schedule"]
+	if !ok {
+		return nil, nil
+	}
+
+	loc := time.UTC
+	if tz, ok := opts["dapr.workflow.schedule_timezone"]; ok {
+		var err error
+		loc, err = time.LoadLocation(tz)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dapr.workflow.schedule_timezone '%s': %w", tz, err)
+		}
+	}
+
+	parsed, err := parseCronSchedule(expr, loc.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid dapr.workflow.schedule cron expression: %w", err)
+	}
+
+	sched := &Schedule{
+		ID:             fmt.Sprintf("%s-schedule-%d", workflowName, time.Now().UnixNano()),
+		WorkflowName:   workflowName,
+		Input:          input,
+		CronExpression: expr,
+		Timezone:       loc.String(),
+		MissedFiring:   missedFiringSkip,
+		CodecName:      codecName,
+		CodecKeyRef:    codecKeyRef,
+		schedule:       parsed,
+	}
+
+	if policy, ok := opts["dapr.workflow.schedule_missed_firing_policy"]; ok {
+		switch missedFiringPolicy(policy) {
+		case missedFiringSkip, missedFiringCatchUp:
+			sched.MissedFiring = missedFiringPolicy(policy)
+		default:
+			return nil, fmt.Errorf("dapr.workflow.schedule_missed_firing_policy must be 'skip' or 'catch_up', got '%s'", policy)
+		}
+	}
+
+	if raw, ok := opts["dapr.workflow.schedule_end_time"]; ok {
+		endTime, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return nil, fmt.Errorf("dapr.workflow.schedule_end_time must be in RFC3339 format: %w", err)
+		}
+		sched.EndTime = &endTime
+	}
+
+	sched.SetNextRun(parsed.Next(time.Now().In(loc)))
+	return sched, nil
+}
+
+// runScheduler ticks every scheduleTickInterval, firing any due, non-paused schedules until ctx
+// is canceled.
+func (c *workflowEngineComponent) runScheduler(ctx context.Context) {
+	ticker := time.NewTicker(scheduleTickInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.fireDueSchedules(ctx)
+		}
+	}
+}
+
+func (c *workflowEngineComponent) fireDueSchedules(ctx context.Context) {
+	schedules, err := c.scheduleStore.LoadSchedules(ctx)
+	if err != nil {
+		c.logger.Warnf("scheduler: failed to load schedules: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for _, sched := range schedules {
+		nextRun := sched.NextRun()
+		if sched.IsPaused() || now.Before(nextRun) {
+			continue
+		}
+		if sched.EndTime != nil && now.After(*sched.EndTime) {
+			continue
+		}
+		// A Schedule loaded from a durable, out-of-package ScheduleStore only carries its exported
+		// fields; Rehydrate populates the internal cron.Schedule from them if it's still nil.
+		if err := sched.Rehydrate(); err != nil {
+			c.logger.Warnf("scheduler: failed to rehydrate schedule '%s': %v", sched.ID, err)
+			continue
+		}
+
+		for _, fireTime := range sched.dueFireTimes(nextRun, now) {
+			c.fireSchedule(ctx, sched, fireTime)
+		}
+
+		sched.SetNextRun(sched.schedule.Next(now))
+		if err := c.scheduleStore.SaveSchedule(ctx, sched); err != nil {
+			c.logger.Warnf("scheduler: failed to persist schedule '%s': %v", sched.ID, err)
+		}
+	}
+}
+
+func (c *workflowEngineComponent) fireSchedule(ctx context.Context, sched *Schedule, fireTime time.Time) {
+	// The fire-time-derived instance ID lets a re-delivered tick (e.g. after a crash mid-fire)
+	// dedup against durabletask-go's existing-instance check instead of double-firing.
+	instanceID := fmt.Sprintf("%s-%d", sched.WorkflowName, fireTime.Unix())
+
+	opts := []api.NewOrchestrationOptions{api.WithInstanceID(api.InstanceID(instanceID))}
+	if sched.Input != nil {
+		opts = append(opts, api.WithInput(sched.Input))
+	}
+
+	if _, err := c.client.ScheduleNewOrchestration(ctx, sched.WorkflowName, opts...); err != nil {
+		c.logger.Warnf("scheduler: failed to fire schedule '%s' for instance '%s': %v", sched.ID, instanceID, err)
+		return
+	}
+
+	c.logger.Infof("scheduler: fired schedule '%s', created workflow instance '%s'", sched.ID, instanceID)
+	c.trackInstance(instanceID, sched.WorkflowName, sched.CodecName, sched.CodecKeyRef)
+}
+
+// ListSchedules returns every schedule currently registered against this component.
+func (c *workflowEngineComponent) ListSchedules(ctx context.Context) ([]*Schedule, error) {
+	return c.scheduleStore.LoadSchedules(ctx)
+}
+
+// PauseSchedule stops a schedule from firing without deleting it; ticks are skipped until
+// SetPaused(false) clears the schedule's paused flag.
+func (c *workflowEngineComponent) PauseSchedule(ctx context.Context, id string) error {
+	schedules, err := c.scheduleStore.LoadSchedules(ctx)
+	if err != nil {
+		return err
+	}
+	for _, sched := range schedules {
+		if sched.ID == id {
+			sched.SetPaused(true)
+			return c.scheduleStore.SaveSchedule(ctx, sched)
+		}
+	}
+	return fmt.Errorf("schedule '%s' not found", id)
+}
+
+// DeleteSchedule removes a schedule so it never fires again.
+func (c *workflowEngineComponent) DeleteSchedule(ctx context.Context, id string) error {
+	return c.scheduleStore.DeleteSchedule(ctx, id)
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package wfengine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/kit/logger"
+)
+
+func TestParseScheduleOptionsNoSchedule(t *testing.T) {
+	sched, err := parseScheduleOptions("wf", nil, map[string]string{}, "", "")
+	require.NoError(t, err)
+	assert.Nil(t, sched)
+}
+
+func TestParseScheduleOptionsDefaultsToSkip(t *testing.T) {
+	sched, err := parseScheduleOptions("wf", nil, map[string]string{
+		"dapr.workflow.schedule": "@every 1m",
+	}, "", "")
+	require.NoError(t, err)
+	require.NotNil(t, sched)
+	assert.Equal(t, missedFiringSkip, sched.MissedFiring)
+	assert.False(t, sched.IsPaused())
+	assert.False(t, sched.NextRun().IsZero())
+}
+
+func TestParseScheduleOptionsRejectsBadPolicy(t *testing.T) {
+	_, err := parseScheduleOptions("wf", nil, map[string]string{
+		"dapr.workflow.schedule":                      "@every 1m",
+		"dapr.workflow.schedule_missed_firing_policy": "sometimes",
+	}, "", "")
+	assert.Error(t, err)
+}
+
+func newTestComponent(store ScheduleStore) *workflowEngineComponent {
+	return &workflowEngineComponent{
+		logger:        logger.NewLogger("wfengine.test"),
+		scheduleStore: store,
+	}
+}
+
+func TestFireDueSchedulesSkipsPaused(t *testing.T) {
+	store := newInMemoryScheduleStore()
+	sched, err := parseScheduleOptions("wf", nil, map[string]string{"dapr.workflow.schedule": "@every 1s"}, "", "")
+	require.NoError(t, err)
+	sched.SetPaused(true)
+	sched.SetNextRun(time.Now().Add(-time.Hour))
+	require.NoError(t, store.SaveSchedule(context.Background(), sched))
+
+	// fireSchedule calls through c.client, which is nil here; a paused schedule must never reach
+	// it, so this only proves correctness if it doesn't panic.
+	c := newTestComponent(store)
+	c.fireDueSchedules(context.Background())
+
+	assert.True(t, sched.NextRun().Before(time.Now()))
+}
+
+func TestDueFireTimesSkipFiresOnceRegardlessOfHowFarBehind(t *testing.T) {
+	sched, err := parseScheduleOptions("wf", nil, map[string]string{"dapr.workflow.schedule": "@every 1s"}, "", "")
+	require.NoError(t, err)
+
+	now := time.Now()
+	fireTimes := sched.dueFireTimes(now.Add(-time.Hour), now)
+
+	assert.Len(t, fireTimes, 1)
+}
+
+func TestDueFireTimesCatchUpFiresEveryMissedTick(t *testing.T) {
+	sched, err := parseScheduleOptions("wf", nil, map[string]string{
+		"dapr.workflow.schedule":                      "@every 1s",
+		"dapr.workflow.schedule_missed_firing_policy": "catch_up",
+	}, "", "")
+	require.NoError(t, err)
+
+	now := time.Now()
+	fireTimes := sched.dueFireTimes(now.Add(-5*time.Second), now)
+
+	assert.GreaterOrEqual(t, len(fireTimes), 4)
+}
+
+func TestPauseScheduleMarksExistingSchedulePaused(t *testing.T) {
+	store := newInMemoryScheduleStore()
+	sched, err := parseScheduleOptions("wf", nil, map[string]string{"dapr.workflow.schedule": "@every 1m"}, "", "")
+	require.NoError(t, err)
+	require.NoError(t, store.SaveSchedule(context.Background(), sched))
+
+	c := newTestComponent(store)
+	require.NoError(t, c.PauseSchedule(context.Background(), sched.ID))
+
+	assert.True(t, sched.IsPaused())
+}
+
+func TestPauseScheduleUnknownIDErrors(t *testing.T) {
+	c := newTestComponent(newInMemoryScheduleStore())
+	err := c.PauseSchedule(context.Background(), "does-not-exist")
+	assert.Error(t, err)
+}
+
+func TestDeleteScheduleRemovesFromStore(t *testing.T) {
+	store := newInMemoryScheduleStore()
+	sched, err := parseScheduleOptions("wf", nil, map[string]string{"dapr.workflow.schedule": "@every 1m"}, "", "")
+	require.NoError(t, err)
+	require.NoError(t, store.SaveSchedule(context.Background(), sched))
+
+	c := newTestComponent(store)
+	require.NoError(t, c.DeleteSchedule(context.Background(), sched.ID))
+
+	schedules, err := c.ListSchedules(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, schedules)
+}
+
+func TestParseScheduleOptionsTracksCodec(t *testing.T) {
+	sched, err := parseScheduleOptions("wf", nil, map[string]string{
+		"dapr.workflow.schedule": "@every 1m",
+	}, "encrypted-json", "mystore/workflow-key")
+	require.NoError(t, err)
+	require.NotNil(t, sched)
+	assert.Equal(t, "encrypted-json", sched.CodecName)
+	assert.Equal(t, "mystore/workflow-key", sched.CodecKeyRef)
+}
+
+func TestRehydrateIsNoopWhenAlreadyParsed(t *testing.T) {
+	sched, err := parseScheduleOptions("wf", nil, map[string]string{"dapr.workflow.schedule": "@every 1m"}, "", "")
+	require.NoError(t, err)
+	require.NoError(t, sched.Rehydrate())
+
+	// Rehydrate leaves the already-parsed schedule in place, and the due tick is still returned.
+	fireTimes := sched.dueFireTimes(sched.NextRun(), sched.NextRun())
+	assert.Len(t, fireTimes, 1)
+}
+
+func TestRehydrateParsesFromPersistedFields(t *testing.T) {
+	// Simulates a Schedule reconstructed by an out-of-package ScheduleStore from its own
+	// persisted rows, which only has the exported fields populated.
+	sched := &Schedule{
+		ID:             "wf-schedule-1",
+		WorkflowName:   "wf",
+		CronExpression: "@every 1m",
+		MissedFiring:   missedFiringSkip,
+	}
+	require.NoError(t, sched.Rehydrate())
+
+	now := time.Now()
+	fireTimes := sched.dueFireTimes(now, now)
+	assert.Len(t, fireTimes, 1)
+}
+
+func TestRehydrateRejectsInvalidCronExpression(t *testing.T) {
+	sched := &Schedule{CronExpression: "not a cron expression"}
+	assert.Error(t, sched.Rehydrate())
+}
@@ -0,0 +1,204 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package wfengine
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	oteltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/microsoft/durabletask-go/api"
+	"github.com/microsoft/durabletask-go/backend"
+)
+
+// tracerName identifies spans emitted by this exporter in the OTel SDK the Dapr runtime already
+// configures, matching the distributed-tracing schema durabletask-go uses for sub-orchestration
+// parent/child links.
+const tracerName = "github.com/dapr/dapr/pkg/runtime/wfengine"
+
+// spanExporterInterval is how often the exporter scans tracked instances for new history events
+// to turn into spans.
+const spanExporterInterval = 2 * time.Second
+
+// runSpanExporter periodically converts newly observed history events for tracked instances into
+// OpenTelemetry spans: one root span per instance, with child spans per activity and
+// sub-orchestration that link to their parent via the scheduling event's ID.
+func (c *workflowEngineComponent) runSpanExporter(ctx context.Context) {
+	ticker := time.NewTicker(spanExporterInterval)
+	defer ticker.Stop()
+
+	exported := make(map[string]map[int32]bool)
+	rootSpanCtx := make(map[string]context.Context)
+	// completed records instances whose spans have been fully exported, independent of exported's
+	// per-event dedup map. It's what actually bounds memory for long-running processes: once an
+	// instance is marked, exported's (much larger) per-event map for it is dropped, but the
+	// instance itself is never rescanned, so its spans aren't re-emitted on every later tick.
+	completed := make(map[string]bool)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.exportPendingSpans(ctx, exported, rootSpanCtx, completed)
+		}
+	}
+}
+
+// exportPendingSpans runs one scan of every tracked instance not yet marked completed, exporting
+// any spans for new history events it finds.
+func (c *workflowEngineComponent) exportPendingSpans(ctx context.Context, exported map[string]map[int32]bool, rootSpanCtx map[string]context.Context, completed map[string]bool) {
+	for _, rec := range c.snapshotInstances() {
+		if completed[rec.instanceID] {
+			continue
+		}
+		c.exportInstanceSpans(ctx, rec.instanceID, exported, rootSpanCtx, completed)
+	}
+}
+
+func (c *workflowEngineComponent) exportInstanceSpans(ctx context.Context, instanceID string, exported map[string]map[int32]bool, rootSpanCtx map[string]context.Context, completed map[string]bool) {
+	events, err := c.historyBackend.GetHistory(ctx, api.InstanceID(instanceID))
+	if err != nil {
+		c.logger.Warnf("tracing: failed to get history for workflow '%s': %v", instanceID, err)
+		return
+	}
+
+	seen, ok := exported[instanceID]
+	if !ok {
+		seen = make(map[int32]bool)
+		exported[instanceID] = seen
+	}
+
+	tracer := otel.Tracer(tracerName)
+	scheduled := make(map[int32]*backend.HistoryEvent)
+
+	for _, e := range events {
+		if e.GetTaskScheduled() != nil || e.GetSubOrchestrationInstanceCreated() != nil {
+			scheduled[e.GetEventId()] = e
+		}
+	}
+
+	instanceCompleted := false
+
+	for _, e := range events {
+		if seen[e.GetEventId()] {
+			continue
+		}
+
+		switch {
+		case e.GetExecutionStarted() != nil:
+			// A sub-orchestration's root span starts under the parent context seeded when the
+			// parent's SubOrchestrationInstanceCreated event was seen, if any.
+			parentCtx := ctx
+			if rc, ok := rootSpanCtx[instanceID]; ok {
+				parentCtx = rc
+			}
+			spanCtx, span := tracer.Start(parentCtx, e.GetExecutionStarted().GetName(),
+				oteltrace.WithTimestamp(e.GetTimestamp().AsTime()),
+				oteltrace.WithAttributes(attribute.String("dapr.workflow.instance_id", instanceID)))
+			rootSpanCtx[instanceID] = spanCtx
+			// The root span is ended once ExecutionCompleted is observed, below; until then it
+			// stays open so child activity/sub-orchestration spans link to it as their parent.
+			_ = span
+
+		case e.GetExecutionCompleted() != nil:
+			if parentCtx, ok := rootSpanCtx[instanceID]; ok {
+				span := oteltrace.SpanFromContext(parentCtx)
+				span.End(oteltrace.WithTimestamp(e.GetTimestamp().AsTime()))
+				delete(rootSpanCtx, instanceID)
+			}
+			// No further events are expected for a completed instance; it's marked done below once
+			// this pass finishes, so a long-running process doesn't keep rescanning it forever.
+			instanceCompleted = true
+
+		case e.GetSubOrchestrationInstanceCreated() != nil:
+			// Track the sub-orchestration under its own instance ID, seeded with this instance's
+			// root span as its parent, so the next exporter pass picks up its ExecutionStarted
+			// event and nests its own activity spans under this call site instead of this
+			// component synthesizing one flat span for the whole sub-orchestration.
+			subInstanceID := e.GetSubOrchestrationInstanceCreated().GetInstanceId()
+			parentCtx := ctx
+			if rc, ok := rootSpanCtx[instanceID]; ok {
+				parentCtx = rc
+			}
+			rootSpanCtx[subInstanceID] = parentCtx
+			c.trackInstance(subInstanceID, e.GetSubOrchestrationInstanceCreated().GetName(), "", "")
+
+		case e.GetTaskCompleted() != nil || e.GetTaskFailed() != nil:
+			startID, ok := relatedScheduledID(e)
+			if !ok {
+				continue
+			}
+			startEvent, ok := scheduled[startID]
+			if !ok {
+				continue
+			}
+
+			parentCtx := ctx
+			if rc, ok := rootSpanCtx[instanceID]; ok {
+				parentCtx = rc
+			}
+
+			_, span := tracer.Start(parentCtx, startEvent.GetTaskScheduled().GetName(),
+				oteltrace.WithTimestamp(startEvent.GetTimestamp().AsTime()),
+				oteltrace.WithAttributes(
+					attribute.String("dapr.workflow.instance_id", instanceID),
+					attribute.Int64("dapr.workflow.task_scheduled_id", int64(startID)),
+				))
+			if e.GetTaskFailed() != nil {
+				span.SetStatus(codes.Error, "workflow task failed")
+			}
+			span.End(oteltrace.WithTimestamp(e.GetTimestamp().AsTime()))
+			seen[startID] = true
+
+		case e.GetSubOrchestrationInstanceCompleted() != nil || e.GetSubOrchestrationInstanceFailed() != nil:
+			// No span to emit here: the sub-orchestration's own ExecutionStarted/ExecutionCompleted
+			// events (tracked above, exported on a later pass once its history exists) already
+			// produce its real span tree, nested under the parentCtx seeded when it was created.
+			startID, ok := relatedScheduledID(e)
+			if ok {
+				seen[startID] = true
+			}
+		}
+
+		seen[e.GetEventId()] = true
+	}
+
+	if instanceCompleted {
+		completed[instanceID] = true
+		delete(exported, instanceID)
+	}
+}
+
+// relatedScheduledID returns the TaskScheduled/SubOrchestrationInstanceCreated event ID a
+// completion/failure event refers back to.
+func relatedScheduledID(e *backend.HistoryEvent) (int32, bool) {
+	switch {
+	case e.GetTaskCompleted() != nil:
+		return e.GetTaskCompleted().GetTaskScheduledId(), true
+	case e.GetTaskFailed() != nil:
+		return e.GetTaskFailed().GetTaskScheduledId(), true
+	case e.GetSubOrchestrationInstanceCompleted() != nil:
+		return e.GetSubOrchestrationInstanceCompleted().GetTaskScheduledId(), true
+	case e.GetSubOrchestrationInstanceFailed() != nil:
+		return e.GetSubOrchestrationInstanceFailed().GetTaskScheduledId(), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package wfengine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/microsoft/durabletask-go/api"
+	"github.com/microsoft/durabletask-go/backend"
+
+	"github.com/dapr/kit/logger"
+)
+
+// countingHistoryBackend implements HistoryBackend, recording how many times GetHistory was
+// called per instance ID, so tests can tell whether an instance was rescanned.
+type countingHistoryBackend struct {
+	calls map[string]int
+}
+
+func (h *countingHistoryBackend) GetHistory(ctx context.Context, instanceID api.InstanceID) ([]*backend.HistoryEvent, error) {
+	if h.calls == nil {
+		h.calls = make(map[string]int)
+	}
+	h.calls[string(instanceID)]++
+	return nil, nil
+}
+
+func TestExportPendingSpansSkipsCompletedInstances(t *testing.T) {
+	hb := &countingHistoryBackend{}
+	c := &workflowEngineComponent{logger: logger.NewLogger("wfengine.test"), historyBackend: hb}
+	c.trackInstance("done", "wf", "", "")
+	c.trackInstance("pending", "wf", "", "")
+
+	exported := make(map[string]map[int32]bool)
+	rootSpanCtx := make(map[string]context.Context)
+	completed := map[string]bool{"done": true}
+
+	c.exportPendingSpans(context.Background(), exported, rootSpanCtx, completed)
+
+	assert.Equal(t, 0, hb.calls["done"], "a completed instance must never be rescanned")
+	assert.Equal(t, 1, hb.calls["pending"])
+}
+
+func TestExportPendingSpansRescansUntilMarkedCompleted(t *testing.T) {
+	hb := &countingHistoryBackend{}
+	c := &workflowEngineComponent{logger: logger.NewLogger("wfengine.test"), historyBackend: hb}
+	c.trackInstance("running", "wf", "", "")
+
+	exported := make(map[string]map[int32]bool)
+	rootSpanCtx := make(map[string]context.Context)
+	completed := make(map[string]bool)
+
+	c.exportPendingSpans(context.Background(), exported, rootSpanCtx, completed)
+	c.exportPendingSpans(context.Background(), exported, rootSpanCtx, completed)
+
+	assert.Equal(t, 2, hb.calls["running"])
+	assert.False(t, completed["running"])
+}
+
+func TestExportInstanceSpansHandlesGetHistoryError(t *testing.T) {
+	c := &workflowEngineComponent{logger: logger.NewLogger("wfengine.test"), historyBackend: &erroringHistoryBackend{}}
+
+	exported := make(map[string]map[int32]bool)
+	rootSpanCtx := make(map[string]context.Context)
+	completed := make(map[string]bool)
+
+	require.NotPanics(t, func() {
+		c.exportInstanceSpans(context.Background(), "instance-1", exported, rootSpanCtx, completed)
+	})
+	assert.False(t, completed["instance-1"])
+}
+
+type erroringHistoryBackend struct{}
+
+func (erroringHistoryBackend) GetHistory(ctx context.Context, instanceID api.InstanceID) ([]*backend.HistoryEvent, error) {
+	return nil, assert.AnError
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package wfengine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/microsoft/durabletask-go/api"
+
+	"github.com/dapr/components-contrib/workflows"
+)
+
+// defaultWatchPollInterval is how often Watch polls the backend for metadata changes. The
+// durabletask-go backends used by Dapr don't currently expose a push-based subscription, so
+// polling is the only option until that lands upstream.
+const defaultWatchPollInterval = 500 * time.Millisecond
+
+// Watch streams StateResponse updates for a workflow instance until it reaches a terminal
+// RuntimeStatus (COMPLETED, FAILED, TERMINATED, or CANCELED) or ctx is canceled, at which point
+// the returned channel is closed.
+func (c *workflowEngineComponent) Watch(ctx context.Context, req *workflows.WorkflowReference) (<-chan *workflows.StateResponse, error) {
+	if req.InstanceID == "" {
+		return nil, fmt.Errorf("a workflow instance ID is required")
+	}
+
+	ch := make(chan *workflows.StateResponse)
+	go c.watchLoop(ctx, req.InstanceID, ch)
+	return ch, nil
+}
+
+func (c *workflowEngineComponent) watchLoop(ctx context.Context, instanceID string, ch chan<- *workflows.StateResponse) {
+	defer close(ch)
+
+	ticker := time.NewTicker(defaultWatchPollInterval)
+	defer ticker.Stop()
+
+	var lastUpdatedAt time.Time
+	lastStatus := int32(-1)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			metadata, err := c.client.FetchOrchestrationMetadata(ctx, api.InstanceID(instanceID))
+			if err != nil {
+				c.logger.Warnf("watch: failed to fetch metadata for workflow '%s': %v", instanceID, err)
+				continue
+			}
+
+			status := int32(metadata.RuntimeStatus)
+			if metadata.LastUpdatedAt.Equal(lastUpdatedAt) && status == lastStatus {
+				continue
+			}
+			lastUpdatedAt = metadata.LastUpdatedAt
+			lastStatus = status
+
+			select {
+			case ch <- c.stateResponseFromMetadata(ctx, instanceID, metadata):
+			case <-ctx.Done():
+				return
+			}
+
+			if isTerminalStatus(status) {
+				return
+			}
+		}
+	}
+}
+
+// isTerminalStatus reports whether a durabletask-go RuntimeStatus value is terminal, i.e. the
+// workflow instance will never produce another Watch update. See getStatusString for the
+// status-code reference.
+func isTerminalStatus(status int32) bool {
+	switch status {
+	case 1, 3, 4, 5: // COMPLETED, FAILED, CANCELED, TERMINATED
+		return true
+	default:
+		return false
+	}
+}
+
+// WatchFunc opens a new Watch stream, as implemented by workflowEngineComponent.Watch.
+type WatchFunc func(ctx context.Context) (<-chan *workflows.StateResponse, error)
+
+// WatchWithReconnect wraps a WatchFunc with automatic reconnection: if the underlying stream
+// closes without having delivered a terminal-state update (e.g. because the backend restarted),
+// it re-invokes watch and keeps forwarding events on the returned channel. This is the pattern
+// gRPC clients should use around a long-lived WatchWorkflows call so a transient backend restart
+// doesn't surface as a premature stream close.
+func WatchWithReconnect(ctx context.Context, watch WatchFunc, backoff time.Duration) <-chan *workflows.StateResponse {
+	out := make(chan *workflows.StateResponse)
+
+	go func() {
+		defer close(out)
+
+		for {
+			in, err := watch(ctx)
+			if err != nil {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(backoff):
+					continue
+				}
+			}
+
+			terminal := false
+		recv:
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case res, ok := <-in:
+					if !ok {
+						break recv
+					}
+					terminal = isTerminalStatus(statusCodeFromMetadata(res))
+					select {
+					case out <- res:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+
+			if terminal || ctx.Err() != nil {
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+		}
+	}()
+
+	return out
+}
+
+// statusCodeFromMetadata recovers the numeric RuntimeStatus encoded by stateResponseFromMetadata
+// so WatchWithReconnect can decide whether a closed stream already reached a terminal state.
+func statusCodeFromMetadata(res *workflows.StateResponse) int32 {
+	for code := int32(0); code <= 7; code++ {
+		if res.Metadata["dapr.workflow.runtime_status"] == getStatusString(code) {
+			return code
+		}
+	}
+	return -1
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package wfengine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/workflows"
+)
+
+func TestIsTerminalStatus(t *testing.T) {
+	terminal := map[int32]bool{1: true, 3: true, 4: true, 5: true}
+	for status := int32(0); status <= 7; status++ {
+		assert.Equal(t, terminal[status], isTerminalStatus(status), "status %d", status)
+	}
+}
+
+func TestStatusCodeFromMetadata(t *testing.T) {
+	res := &workflows.StateResponse{Metadata: map[string]string{"dapr.workflow.runtime_status": "COMPLETED"}}
+	assert.Equal(t, int32(1), statusCodeFromMetadata(res))
+
+	unknown := &workflows.StateResponse{Metadata: map[string]string{"dapr.workflow.runtime_status": "NOT_A_STATUS"}}
+	assert.Equal(t, int32(-1), statusCodeFromMetadata(unknown))
+}
+
+func TestWatchWithReconnectForwardsUntilTerminal(t *testing.T) {
+	calls := 0
+	watch := func(ctx context.Context) (<-chan *workflows.StateResponse, error) {
+		calls++
+		ch := make(chan *workflows.StateResponse, 2)
+		ch <- &workflows.StateResponse{Metadata: map[string]string{"dapr.workflow.runtime_status": "RUNNING"}}
+		ch <- &workflows.StateResponse{Metadata: map[string]string{"dapr.workflow.runtime_status": "COMPLETED"}}
+		close(ch)
+		return ch, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	out := WatchWithReconnect(ctx, watch, time.Millisecond)
+
+	var received []string
+	for res := range out {
+		received = append(received, res.Metadata["dapr.workflow.runtime_status"])
+	}
+
+	assert.Equal(t, []string{"RUNNING", "COMPLETED"}, received)
+	assert.Equal(t, 1, calls, "a terminal status must not trigger a reconnect")
+}
+
+func TestWatchWithReconnectReconnectsOnNonTerminalClose(t *testing.T) {
+	calls := 0
+	watch := func(ctx context.Context) (<-chan *workflows.StateResponse, error) {
+		calls++
+		ch := make(chan *workflows.StateResponse, 1)
+		if calls == 1 {
+			ch <- &workflows.StateResponse{Metadata: map[string]string{"dapr.workflow.runtime_status": "RUNNING"}}
+			close(ch)
+			return ch, nil
+		}
+		ch <- &workflows.StateResponse{Metadata: map[string]string{"dapr.workflow.runtime_status": "COMPLETED"}}
+		close(ch)
+		return ch, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	out := WatchWithReconnect(ctx, watch, time.Millisecond)
+
+	var received []string
+	for res := range out {
+		received = append(received, res.Metadata["dapr.workflow.runtime_status"])
+	}
+
+	assert.Equal(t, []string{"RUNNING", "COMPLETED"}, received)
+	assert.Equal(t, 2, calls, "a non-terminal stream close must trigger a reconnect")
+}
+
+func TestWatchWithReconnectRetriesOnWatchError(t *testing.T) {
+	calls := 0
+	watch := func(ctx context.Context) (<-chan *workflows.StateResponse, error) {
+		calls++
+		if calls == 1 {
+			return nil, assert.AnError
+		}
+		ch := make(chan *workflows.StateResponse, 1)
+		ch <- &workflows.StateResponse{Metadata: map[string]string{"dapr.workflow.runtime_status": "COMPLETED"}}
+		close(ch)
+		return ch, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	out := WatchWithReconnect(ctx, watch, time.Millisecond)
+
+	var received []string
+	for res := range out {
+		received = append(received, res.Metadata["dapr.workflow.runtime_status"])
+	}
+
+	require.Equal(t, []string{"COMPLETED"}, received)
+	assert.Equal(t, 2, calls)
+}
+
+func TestWatchWithReconnectStopsOnContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	blocked := make(chan *workflows.StateResponse)
+	watch := func(ctx context.Context) (<-chan *workflows.StateResponse, error) {
+		return blocked, nil
+	}
+
+	out := WatchWithReconnect(ctx, watch, time.Millisecond)
+	cancel()
+
+	select {
+	case _, ok := <-out:
+		assert.False(t, ok, "the output channel must close once ctx is canceled")
+	case <-time.After(2 * time.Second):
+		t.Fatal("WatchWithReconnect did not stop after ctx was canceled")
+	}
+}